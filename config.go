@@ -0,0 +1,181 @@
+package metric
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config describes a set of metrics to be created at startup and the sinks
+// they are exported to
+type Config struct {
+	Metrics []MetricConfig `json:"metrics"`
+	Sinks   []SinkConfig   `json:"sinks,omitempty"`
+}
+
+// MetricConfig describes a single metric. Frame is either empty (single
+// current value) or a pair of durations: total history and precision,
+// e.g. ["15m", "10s"].
+type MetricConfig struct {
+	Name   string            `json:"name"`
+	Type   string            `json:"type"`
+	Frame  []string          `json:"frame,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SinkConfig describes a single export destination:
+//   - "statsd" sends metrics to Address (host:port) over UDP with an optional
+//     name Prefix
+//   - "prometheus" serves metrics for scraping at http://Address/metrics
+//   - "push" POSTs metrics in Prometheus text format to URL, e.g. a Pushgateway
+type SinkConfig struct {
+	Type    string `json:"type"`
+	Address string `json:"address,omitempty"`
+	Prefix  string `json:"prefix,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// Config decoders by file extension. Only JSON is supported to keep the
+// package free of dependencies; YAML can be added here without changing
+// LoadConfigFile.
+var configDecoders = map[string]func(r io.Reader) (Config, error){
+	".json": decodeJSONConfig,
+}
+
+// LoadConfigFile reads config from the given path and returns a registry
+// with the configured metrics and sinks. The format is chosen by file
+// extension; only ".json" is supported, see LoadConfig.
+func LoadConfigFile(path string) (*Registry, error) {
+	decode, ok := configDecoders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("metric: unsupported config format %q", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build(now())
+}
+
+// LoadConfig decodes JSON config and returns a registry with the configured
+// metrics and sinks. All timelines share the same frame start. Unknown
+// fields are rejected. YAML is not supported.
+func LoadConfig(r io.Reader) (*Registry, error) {
+	cfg, err := decodeJSONConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Build(now())
+}
+
+func decodeJSONConfig(r io.Reader) (Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, err
+	}
+	if dec.More() {
+		return cfg, fmt.Errorf("metric: unexpected data after config")
+	}
+	return cfg, nil
+}
+
+// Build creates a registry with metrics and sinks described by the config.
+// Every timeline starts its first frame at frameStart. A zero precision
+// means one minute and a zero history means 15 frames of the precision.
+// Sinks that were already opened are closed if the config is invalid.
+func (cfg Config) Build(frameStart time.Time) (*Registry, error) {
+	r := NewRegistry()
+	if err := cfg.build(r, frameStart); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (cfg Config) build(r *Registry, frameStart time.Time) error {
+	for _, mc := range cfg.Metrics {
+		m, err := mc.build(frameStart)
+		if err != nil {
+			return err
+		}
+		if err := r.Register(mc.Name, m, mc.Labels); err != nil {
+			return err
+		}
+	}
+	for _, sc := range cfg.Sinks {
+		s, err := sc.build(r)
+		if err != nil {
+			return err
+		}
+		r.AddSink(s)
+	}
+	return nil
+}
+
+func (sc SinkConfig) build(r *Registry) (Sink, error) {
+	switch sc.Type {
+	case "statsd":
+		if sc.Address == "" {
+			return nil, fmt.Errorf("metric: statsd sink: missing address")
+		}
+		return NewStatsDSink(sc.Address, sc.Prefix)
+	case "prometheus":
+		if sc.Address == "" {
+			return nil, fmt.Errorf("metric: prometheus sink: missing address")
+		}
+		return NewPrometheusSink(r, sc.Address)
+	case "push":
+		if sc.URL == "" {
+			return nil, fmt.Errorf("metric: push sink: missing url")
+		}
+		return NewPushSink(sc.URL)
+	default:
+		return nil, fmt.Errorf("metric: unsupported sink type %q", sc.Type)
+	}
+}
+
+func (mc MetricConfig) build(frameStart time.Time) (Metric, error) {
+	if len(mc.Frame) != 0 && len(mc.Frame) != 2 {
+		return nil, fmt.Errorf("metric: %q: frame must have 0 or 2 durations", mc.Name)
+	}
+	frame := make([]time.Duration, len(mc.Frame))
+	for i, s := range mc.Frame {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("metric: %q: %v", mc.Name, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("metric: %q: negative duration %q", mc.Name, s)
+		}
+		frame[i] = d
+	}
+	if len(frame) == 2 {
+		// Same defaults as newTimeseries
+		if frame[1] == 0 {
+			frame[1] = time.Minute
+		}
+		if frame[0] == 0 {
+			frame[0] = frame[1] * 15
+		}
+		if frame[0]/frame[1] < 1 {
+			return nil, fmt.Errorf("metric: %q: history is shorter than precision", mc.Name)
+		}
+	}
+	switch mc.Type {
+	case "counter":
+		return NewCounter(frameStart, frame...), nil
+	default:
+		return nil, fmt.Errorf("metric: %q: unsupported type %q", mc.Name, mc.Type)
+	}
+}
@@ -0,0 +1,132 @@
+package metric
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	now = mockTime(0)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var pushed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		pushed = string(b)
+	}))
+	defer srv.Close()
+	r, err := LoadConfig(strings.NewReader(`{"metrics": [
+		{"name": "requests", "type": "counter", "labels": {"host": "a"}},
+		{"name": "errors", "type": "counter", "frame": ["3s", "1s"]}
+	], "sinks": [
+		{"type": "statsd", "address": "` + conn.LocalAddr().String() + `", "prefix": "app"},
+		{"type": "prometheus", "address": "127.0.0.1:0"},
+		{"type": "push", "url": "` + srv.URL + `/metrics/job/test"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	count := func(x float64) h { return h{"type": "c", "count": x} }
+	r.Get("requests").Add(1)
+	assertJSON(t, r.Get("requests"), count(1))
+	r.Get("errors").Add(2)
+	assertJSON(t, r.Get("errors"), h{"interval": 1, "samples": v{count(2), count(0), count(0)}})
+	if r.Get("missing") != nil {
+		t.Fatal("unexpected metric")
+	}
+
+	if err := r.Export(); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 512)
+	for _, expect := range []string{"app.requests:1|g|#host:a", "app.errors:2|g"} {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != expect {
+			t.Fatal(string(buf[:n]), expect)
+		}
+	}
+	if !strings.Contains(pushed, `requests{host="a"} 1`) {
+		t.Fatal(pushed)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metric")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"metrics.json": `{"metrics": [{"name": "requests", "type": "counter"}]}`,
+		"metrics.yaml": "metrics: []",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r, err := LoadConfigFile(filepath.Join(dir, "metrics.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("requests") == nil {
+		t.Fatal("missing metric")
+	}
+	if _, err := LoadConfigFile(filepath.Join(dir, "metrics.yaml")); err == nil || !strings.Contains(err.Error(), "unsupported config format") {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFile(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		cfg string
+		err string
+	}{
+		{`{"metrics": [{"name": "x", "type": "gauge"}]}`, `unsupported type "gauge"`},
+		{`{"metrics": [{"name": "x", "type": "c"}]}`, `unsupported type "c"`},
+		{`{"metrics": [{"type": "counter"}]}`, "missing metric name"},
+		{`{"metrics": [{"name": "a|b", "type": "counter"}]}`, "invalid metric name"},
+		{`{"metrics": [{"name": "a:b", "type": "counter"}]}`, "invalid metric name"},
+		{`{"metrics": [{"name": "x", "type": "counter", "labels": {"a,b": "v"}}]}`, "invalid label name"},
+		{`{"metrics": [{"name": "x", "type": "counter", "labels": {"k": "a#b"}}]}`, "invalid label value"},
+		{`{"metrics": [{"name": "x", "type": "counter", "labels": {"k": "a\nb"}}]}`, "invalid label value"},
+		{`{"metrics": [{"name": "x", "type": "counter"}, {"name": "x", "type": "counter"}]}`, "duplicate metric"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["1m"]}]}`, "frame must have 0 or 2 durations"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["1m", "foo"]}]}`, "invalid duration"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["1m", "-1s"]}]}`, "negative duration"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["1s", "1m"]}]}`, "history is shorter than precision"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["30s", "0s"]}]}`, "history is shorter than precision"},
+		{`{"metrics": [{"name": "x", "type": "counter", "frame": ["1s", "0"]}]}`, "history is shorter than precision"},
+		{`{"metrics": [{"name": "x", "type": "counter", "help": "x"}]}`, `unknown field "help"`},
+		{`{"metrics": [], "sinks": [{"type": "kafka"}]}`, `unsupported sink type "kafka"`},
+		{`{"metrics": [], "sinks": [{"type": "prometheus"}]}`, "prometheus sink: missing address"},
+		{`{"metrics": [], "sinks": [{"type": "push"}]}`, "push sink: missing url"},
+		{`{"metrics": [], "sinks": [{"type": "push", "url": "localhost:9091"}]}`, "push sink: invalid url"},
+		{`{"metrics": [], "sinks": [{"type": "statsd"}]}`, "statsd sink: missing address"},
+		{`{"metrics": [], "sinks": [{"type": "statsd", "address": "127.0.0.1:8125", "prefix": "a|b"}]}`, "invalid prefix"},
+		{`{"metrics": []} garbage`, "unexpected data after config"},
+		{`{"metrics": []} {"metrics": []}`, "unexpected data after config"},
+	} {
+		_, err := LoadConfig(strings.NewReader(test.cfg))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Fatal(test.cfg, err)
+		}
+	}
+}
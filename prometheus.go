@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4"
+
+var prometheusEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus writes all registry metrics in Prometheus text format.
+// Metrics without history are counters, metrics with history are gauges
+// holding the value of the most recent frame.
+func writePrometheus(w io.Writer, r *Registry) error {
+	var b bytes.Buffer
+	r.Each(func(name string, labels map[string]string, m Metric) {
+		values := m.Get()
+		if len(values) == 0 {
+			return
+		}
+		typ := "gauge"
+		if _, ok := m.(*counter); ok {
+			typ = "counter"
+		}
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, typ)
+		b.WriteString(name)
+		if len(labels) > 0 {
+			keys := make([]string, 0, len(labels))
+			for k := range labels {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			b.WriteByte('{')
+			for i, k := range keys {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				fmt.Fprintf(&b, `%s="%s"`, k, prometheusEscaper.Replace(labels[k]))
+			}
+			b.WriteByte('}')
+		}
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatFloat(values[0], 'f', -1, 64))
+		b.WriteByte('\n')
+	})
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// PrometheusHandler returns a handler that serves registry metrics in
+// Prometheus text format.
+func PrometheusHandler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		writePrometheus(w, r)
+	})
+}
+
+type prometheusSink struct {
+	ln  net.Listener
+	srv *http.Server
+}
+
+// NewPrometheusSink starts an HTTP server on addr that serves registry
+// metrics at /metrics. Prometheus scrapes metrics itself, so Export is a
+// no-op; Close stops the server.
+func NewPrometheusSink(r *Registry, addr string) (Sink, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", PrometheusHandler(r))
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	return &prometheusSink{ln: ln, srv: srv}, nil
+}
+
+func (s *prometheusSink) Export(r *Registry) error { return nil }
+func (s *prometheusSink) Close() error             { return s.srv.Close() }
+
+type pushSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewPushSink returns a sink that POSTs registry metrics in Prometheus text
+// format to rawurl on each Export, e.g. to a Pushgateway at
+// "http://host:9091/metrics/job/app".
+func NewPushSink(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("metric: push sink: invalid url %q", rawurl)
+	}
+	return &pushSink{url: rawurl, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (s *pushSink) Export(r *Registry) error {
+	var b bytes.Buffer
+	if err := writePrometheus(&b, r); err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, prometheusContentType, &b)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("metric: push sink: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *pushSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,99 @@
+package metric
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	now = mockTime(0)
+	r := NewRegistry()
+	if err := r.Register("requests", NewCounter(now()), map[string]string{"host": "a", "dc": `x"y`}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("errors", NewCounter(now(), 3*time.Second, time.Second), nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Get("requests").Add(1)
+	r.Get("errors").Add(2)
+	return r
+}
+
+const testPrometheusText = `# TYPE requests counter
+requests{dc="x\"y",host="a"} 1
+# TYPE errors gauge
+errors 2
+`
+
+func TestPrometheusHandler(t *testing.T) {
+	srv := httptest.NewServer(PrometheusHandler(testRegistry(t)))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("Content-Type") != prometheusContentType {
+		t.Fatal(resp.Header)
+	}
+	if string(body) != testPrometheusText {
+		t.Fatal(string(body))
+	}
+}
+
+func TestPrometheusSink(t *testing.T) {
+	r := testRegistry(t)
+	s, err := NewPrometheusSink(r, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + s.(*prometheusSink).ln.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != testPrometheusText {
+		t.Fatal(string(body))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPushSink(t *testing.T) {
+	var method, contentType, body string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		method, contentType, body = req.Method, req.Header.Get("Content-Type"), string(b)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	r := testRegistry(t)
+	s, err := NewPushSink(srv.URL + "/metrics/job/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.AddSink(s)
+	defer r.Close()
+	if err := r.Export(); err != nil {
+		t.Fatal(err)
+	}
+	if method != "POST" || contentType != prometheusContentType || body != testPrometheusText {
+		t.Fatal(method, contentType, body)
+	}
+	status = http.StatusInternalServerError
+	if err := r.Export(); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,131 @@
+package metric
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Names and label keys must be valid for every sink, label values must not
+// contain StatsD separators
+var (
+	validName   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	labelUnsafe = ":|,#\n\r"
+)
+
+// Sink exports all metrics of a registry to some external destination
+type Sink interface {
+	Export(r *Registry) error
+	// Close releases resources held by the sink, e.g. network connections
+	Close() error
+}
+
+// Registry keeps named metrics with their labels and the sinks they are
+// exported to
+type Registry struct {
+	mu      sync.Mutex
+	names   []string
+	metrics map[string]*entry
+	sinks   []Sink
+}
+
+type entry struct {
+	metric Metric
+	labels map[string]string
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]*entry{}}
+}
+
+// Register adds a metric under the given name. Labels may be nil. Names and
+// label keys may contain only letters, digits and underscores, label values
+// may not contain any of ":|,#" or line breaks.
+func (r *Registry) Register(name string, m Metric, labels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == "" {
+		return fmt.Errorf("metric: missing metric name")
+	}
+	if !validName.MatchString(name) {
+		return fmt.Errorf("metric: invalid metric name %q", name)
+	}
+	for k, v := range labels {
+		if !validName.MatchString(k) {
+			return fmt.Errorf("metric: %q: invalid label name %q", name, k)
+		}
+		if strings.ContainsAny(v, labelUnsafe) {
+			return fmt.Errorf("metric: %q: invalid label value %q", name, v)
+		}
+	}
+	if _, ok := r.metrics[name]; ok {
+		return fmt.Errorf("metric: duplicate metric %q", name)
+	}
+	r.names = append(r.names, name)
+	r.metrics[name] = &entry{metric: m, labels: labels}
+	return nil
+}
+
+// Get returns the metric registered under the given name or nil
+func (r *Registry) Get(name string) Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.metrics[name]; ok {
+		return e.metric
+	}
+	return nil
+}
+
+// Each calls f for every metric in registration order
+func (r *Registry) Each(f func(name string, labels map[string]string, m Metric)) {
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	entries := make([]*entry, len(names))
+	for i, name := range names {
+		entries[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+	for i, e := range entries {
+		f(names[i], e.labels, e.metric)
+	}
+}
+
+// AddSink attaches a sink that will receive metrics on each Export call
+func (r *Registry) AddSink(s Sink) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sinks = append(r.sinks, s)
+}
+
+// Export sends all metrics to every attached sink. It tries all sinks and
+// returns the first error, if any.
+func (r *Registry) Export() error {
+	r.mu.Lock()
+	sinks := append([]Sink(nil), r.sinks...)
+	r.mu.Unlock()
+	var err error
+	for _, s := range sinks {
+		if e := s.Export(r); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close closes and detaches every attached sink. It tries all sinks and
+// returns the first error, if any.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	sinks := r.sinks
+	r.sinks = nil
+	r.mu.Unlock()
+	var err error
+	for _, s := range sinks {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
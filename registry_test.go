@@ -0,0 +1,46 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+)
+
+type sinkFunc func(r *Registry) error
+
+func (f sinkFunc) Export(r *Registry) error { return f(r) }
+func (f sinkFunc) Close() error             { return f(nil) }
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("b", NewCounter(now()), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("a", NewCounter(now()), map[string]string{"k": "v"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("a", NewCounter(now()), nil); err == nil {
+		t.Fatal("duplicate metric registered")
+	}
+	if err := r.Register("", NewCounter(now()), nil); err == nil {
+		t.Fatal("unnamed metric registered")
+	}
+	var names []string
+	r.Each(func(name string, labels map[string]string, m Metric) { names = append(names, name) })
+	if len(names) != 2 || names[0] != "b" || names[1] != "a" {
+		t.Fatal(names)
+	}
+
+	calls := 0
+	fail := errors.New("fail")
+	r.AddSink(sinkFunc(func(*Registry) error { calls++; return fail }))
+	r.AddSink(sinkFunc(func(*Registry) error { calls++; return nil }))
+	if err := r.Export(); err != fail || calls != 2 {
+		t.Fatal(err, calls)
+	}
+	if err := r.Close(); err != fail || calls != 4 {
+		t.Fatal(err, calls)
+	}
+	if err := r.Close(); err != nil || calls != 4 {
+		t.Fatal("sinks closed twice", err, calls)
+	}
+}
@@ -0,0 +1,78 @@
+package metric
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var validPrefix = regexp.MustCompile(`^[a-zA-Z0-9_.]*$`)
+
+type statsdSink struct {
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsDSink returns a sink that sends metrics over UDP to a StatsD
+// server at addr. Each metric is sent as a gauge with its current value
+// (the most recent frame for metrics with history), so counters carry the
+// cumulative value rather than "|c" deltas. Labels are sent as
+// DogStatsD-style tags. A non-empty prefix is prepended as "prefix.name".
+func NewStatsDSink(addr, prefix string) (Sink, error) {
+	if !validPrefix.MatchString(prefix) {
+		return nil, fmt.Errorf("metric: statsd sink: invalid prefix %q", prefix)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &statsdSink{conn: conn, prefix: prefix}, nil
+}
+
+func (s *statsdSink) Export(r *Registry) error {
+	var err error
+	r.Each(func(name string, labels map[string]string, m Metric) {
+		values := m.Get()
+		if len(values) == 0 {
+			return
+		}
+		if _, e := s.conn.Write(s.line(name, labels, values[0])); e != nil && err == nil {
+			err = e
+		}
+	})
+	return err
+}
+
+func (s *statsdSink) Close() error { return s.conn.Close() }
+
+func (s *statsdSink) line(name string, labels map[string]string, value float64) []byte {
+	var b bytes.Buffer
+	if s.prefix != "" {
+		b.WriteString(s.prefix)
+		b.WriteByte('.')
+	}
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	b.WriteString("|g")
+	if len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteString("|#")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(k)
+			b.WriteByte(':')
+			b.WriteString(labels[k])
+		}
+	}
+	return b.Bytes()
+}